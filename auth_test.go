@@ -0,0 +1,212 @@
+package main
+
+import (
+    "crypto/ed25519"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/golang-jwt/jwt/v4"
+)
+
+// withTestJWTKey swaps the package-level jwtPublicKey for the duration
+// of a test and restores it afterward, so tests don't depend on a real
+// JWT_PUBKEY environment variable being set.
+func withTestJWTKey(t *testing.T, pub ed25519.PublicKey) {
+    t.Helper()
+    orig := jwtPublicKey
+    jwtPublicKey = pub
+    t.Cleanup(func() { jwtPublicKey = orig })
+}
+
+func signToken(t *testing.T, priv ed25519.PrivateKey, claims jwt.RegisteredClaims) string {
+    t.Helper()
+    token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+    signed, err := token.SignedString(priv)
+    if err != nil {
+        t.Fatalf("failed to sign test token: %v", err)
+    }
+    return signed
+}
+
+func TestVerifyBearerToken_Valid(t *testing.T) {
+    pub, priv, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    withTestJWTKey(t, pub)
+
+    token := signToken(t, priv, jwt.RegisteredClaims{
+        Subject:   "alice",
+        ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+    })
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+
+    sub, err := verifyBearerToken(req)
+    if err != nil {
+        t.Fatalf("verifyBearerToken returned error: %v", err)
+    }
+    if sub != "alice" {
+        t.Errorf("sub = %q, want %q", sub, "alice")
+    }
+}
+
+func TestVerifyBearerToken_Expired(t *testing.T) {
+    pub, priv, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    withTestJWTKey(t, pub)
+
+    token := signToken(t, priv, jwt.RegisteredClaims{
+        Subject:   "alice",
+        ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+    })
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+
+    if _, err := verifyBearerToken(req); err == nil {
+        t.Error("expected an error for an expired token, got nil")
+    }
+}
+
+func TestVerifyBearerToken_MissingSubject(t *testing.T) {
+    pub, priv, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    withTestJWTKey(t, pub)
+
+    token := signToken(t, priv, jwt.RegisteredClaims{
+        ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+    })
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+
+    if _, err := verifyBearerToken(req); err == nil {
+        t.Error("expected an error for a token with no 'sub' claim, got nil")
+    }
+}
+
+func TestVerifyBearerToken_MissingHeader(t *testing.T) {
+    pub, _, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    withTestJWTKey(t, pub)
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    if _, err := verifyBearerToken(req); err != errMissingBearerToken {
+        t.Errorf("err = %v, want errMissingBearerToken", err)
+    }
+}
+
+// TestVerifyBearerToken_AlgConfusion guards against the classic
+// asymmetric-to-symmetric downgrade attack: a token signed HS256 using
+// the Ed25519 public key's raw bytes as an HMAC secret must still be
+// rejected, since verifyBearerToken's keyfunc only accepts
+// SigningMethodEd25519.
+func TestVerifyBearerToken_AlgConfusion(t *testing.T) {
+    pub, _, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    withTestJWTKey(t, pub)
+
+    claims := jwt.RegisteredClaims{
+        Subject:   "alice",
+        ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+    }
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    signed, err := token.SignedString([]byte(pub))
+    if err != nil {
+        t.Fatalf("failed to sign confusion token: %v", err)
+    }
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    req.Header.Set("Authorization", "Bearer "+signed)
+
+    if _, err := verifyBearerToken(req); err == nil {
+        t.Error("expected alg-confusion token to be rejected, got nil error")
+    }
+}
+
+func TestJWTAuthMiddleware_NoKeyConfigured(t *testing.T) {
+    withTestJWTKey(t, nil)
+
+    called := false
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        called = true
+        w.WriteHeader(http.StatusOK)
+    })
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    rec := httptest.NewRecorder()
+    JWTAuthMiddleware(next).ServeHTTP(rec, req)
+
+    if !called {
+        t.Error("expected next handler to be called when no JWT_PUBKEY is configured")
+    }
+    if rec.Code != http.StatusOK {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+    }
+}
+
+func TestJWTAuthMiddleware_RejectsUnauthenticated(t *testing.T) {
+    pub, _, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    withTestJWTKey(t, pub)
+
+    before := atomic.LoadInt64(&authFailureCount)
+
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        t.Error("next handler should not be called for an unauthenticated request")
+    })
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    rec := httptest.NewRecorder()
+    JWTAuthMiddleware(next).ServeHTTP(rec, req)
+
+    if rec.Code != http.StatusUnauthorized {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+    }
+    if after := atomic.LoadInt64(&authFailureCount); after != before+1 {
+        t.Errorf("authFailureCount = %d, want %d", after, before+1)
+    }
+}
+
+func TestJWTAuthMiddleware_PropagatesSubject(t *testing.T) {
+    pub, priv, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    withTestJWTKey(t, pub)
+
+    token := signToken(t, priv, jwt.RegisteredClaims{
+        Subject:   "bob",
+        ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+    })
+
+    var gotSub string
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotSub = subjectFromContext(r.Context())
+        w.WriteHeader(http.StatusOK)
+    })
+
+    req := httptest.NewRequest("POST", "/format", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    rec := httptest.NewRecorder()
+    JWTAuthMiddleware(next).ServeHTTP(rec, req)
+
+    if gotSub != "bob" {
+        t.Errorf("subject in context = %q, want %q", gotSub, "bob")
+    }
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+    "errors"
+    "net/http"
+    "os"
+    "strconv"
+    "time"
+)
+
+// defaultMaxBodyBytes is the request body size limit used when
+// MAX_BODY_BYTES is unset or invalid.
+const defaultMaxBodyBytes = 10 << 20 // 10 MiB
+
+// maxBodyBytes bounds how much of a request body formatHandler and
+// FormatMetricsMiddleware will read, so a client cannot exhaust server
+// memory with an unbounded or malicious payload.
+var maxBodyBytes = loadMaxBodyBytes()
+
+func loadMaxBodyBytes() int64 {
+    v := os.Getenv("MAX_BODY_BYTES")
+    if v == "" {
+        return defaultMaxBodyBytes
+    }
+    n, err := strconv.ParseInt(v, 10, 64)
+    if err != nil || n <= 0 {
+        return defaultMaxBodyBytes
+    }
+    return n
+}
+
+// isBodyTooLargeErr reports whether err came from an http.MaxBytesReader
+// rejecting a body that exceeded its limit.
+func isBodyTooLargeErr(err error) bool {
+    var maxBytesErr *http.MaxBytesError
+    return errors.As(err, &maxBytesErr)
+}
+
+// Server timeouts. http.ListenAndServe's zero-value defaults leave
+// reads, writes, and idle connections unbounded, which is exactly what
+// lets a slow or stalled client hold a connection (and its goroutine)
+// open indefinitely; streaming large payloads is the one case where the
+// write side has to run long on purpose, which is why streamGuard winds
+// a stream down before WriteTimeout actually fires.
+const (
+    defaultReadTimeout  = 30 * time.Second
+    defaultWriteTimeout = 2 * time.Minute
+    defaultIdleTimeout  = 90 * time.Second
+)
+
+var (
+    serverReadTimeout  = loadTimeoutEnv("READ_TIMEOUT_SECONDS", defaultReadTimeout)
+    serverWriteTimeout = loadTimeoutEnv("WRITE_TIMEOUT_SECONDS", defaultWriteTimeout)
+    serverIdleTimeout  = loadTimeoutEnv("IDLE_TIMEOUT_SECONDS", defaultIdleTimeout)
+)
+
+func loadTimeoutEnv(name string, fallback time.Duration) time.Duration {
+    v := os.Getenv(name)
+    if v == "" {
+        return fallback
+    }
+    n, err := strconv.Atoi(v)
+    if err != nil || n <= 0 {
+        return fallback
+    }
+    return time.Duration(n) * time.Second
+}
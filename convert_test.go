@@ -0,0 +1,132 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestConvertFormat_JSONToYAML(t *testing.T) {
+    out, err := convertFormat("json", "yaml", []byte(`{"a":1,"b":"two"}`), FormatOptions{IndentWidth: 2, SortKeys: true})
+    if err != nil {
+        t.Fatalf("convertFormat returned error: %v", err)
+    }
+    if !strings.Contains(string(out), "a: 1") || !strings.Contains(string(out), "b: two") {
+        t.Errorf("converted YAML missing expected fields: %q", out)
+    }
+}
+
+func TestConvertFormat_RoundTrip(t *testing.T) {
+    original := []byte(`{"name":"test","count":3}`)
+
+    yamlOut, err := convertFormat("json", "yaml", original, FormatOptions{IndentWidth: 2, SortKeys: true})
+    if err != nil {
+        t.Fatalf("json->yaml failed: %v", err)
+    }
+    backToJSON, err := convertFormat("yaml", "json", yamlOut, FormatOptions{IndentWidth: 2, SortKeys: true})
+    if err != nil {
+        t.Fatalf("yaml->json failed: %v", err)
+    }
+
+    var got map[string]interface{}
+    if err := json.Unmarshal(backToJSON, &got); err != nil {
+        t.Fatalf("round-tripped output is not valid JSON: %v", err)
+    }
+    if got["name"] != "test" || got["count"].(float64) != 3 {
+        t.Errorf("round-tripped value = %v, want name=test count=3", got)
+    }
+}
+
+func TestConvertFormat_UnbridgeablePair(t *testing.T) {
+    _, err := convertFormat("html", "json", []byte(`<p>hi</p>`), FormatOptions{})
+    if err == nil {
+        t.Fatal("expected an error converting html to json, got nil")
+    }
+    if _, ok := err.(*unbridgeableFormatsError); !ok {
+        t.Errorf("err = %T(%v), want *unbridgeableFormatsError", err, err)
+    }
+}
+
+func TestFormatHandler_ConvertsBetweenFormats(t *testing.T) {
+    body := strings.NewReader(`{"a":1}`)
+    req := httptest.NewRequest("POST", "/format?type=json&to=yaml", body)
+    rec := httptest.NewRecorder()
+
+    formatHandler(rec, req)
+
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d, body = %q", rec.Code, rec.Body.String())
+    }
+    if ct := rec.Header().Get("Content-Type"); ct != contentTypeFor("yaml") {
+        t.Errorf("Content-Type = %q, want %q", ct, contentTypeFor("yaml"))
+    }
+    if !strings.Contains(rec.Body.String(), "a: 1") {
+        t.Errorf("response body missing converted content: %q", rec.Body.String())
+    }
+}
+
+func TestFormatHandler_UnbridgeablePairReturns415(t *testing.T) {
+    body := strings.NewReader(`<p>hi</p>`)
+    req := httptest.NewRequest("POST", "/format?type=html&to=json", body)
+    rec := httptest.NewRecorder()
+
+    formatHandler(rec, req)
+
+    if rec.Code != http.StatusUnsupportedMediaType {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+    }
+}
+
+func TestFormatHandler_HonorsAcceptHeaderWhenToUnset(t *testing.T) {
+    body := strings.NewReader(`{"a":1}`)
+    req := httptest.NewRequest("POST", "/format?type=json", body)
+    req.Header.Set("Accept", "application/yaml")
+    rec := httptest.NewRecorder()
+
+    formatHandler(rec, req)
+
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d, body = %q", rec.Code, rec.Body.String())
+    }
+    if ct := rec.Header().Get("Content-Type"); ct != contentTypeFor("yaml") {
+        t.Errorf("Content-Type = %q, want %q", ct, contentTypeFor("yaml"))
+    }
+}
+
+func TestFormatsHandler(t *testing.T) {
+    req := httptest.NewRequest("GET", "/formats", nil)
+    rec := httptest.NewRecorder()
+
+    FormatsHandler(rec, req)
+
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d", rec.Code)
+    }
+
+    var resp formatsResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+        t.Fatalf("response is not valid JSON: %v", err)
+    }
+
+    foundJSON := false
+    for _, typ := range resp.Types {
+        if typ == "json" {
+            foundJSON = true
+        }
+    }
+    if !foundJSON {
+        t.Errorf("types = %v, want it to include %q", resp.Types, "json")
+    }
+
+    foundJSONToYAML := false
+    for _, p := range resp.Conversions {
+        if p.From == "json" && p.To == "yaml" {
+            foundJSONToYAML = true
+        }
+    }
+    if !foundJSONToYAML {
+        t.Errorf("conversions = %v, want a json->yaml pair", resp.Conversions)
+    }
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// FormatOptions carries the per-request knobs a Formatter may honor.
+// Not every formatter uses every field; unsupported options are ignored.
+type FormatOptions struct {
+    IndentWidth int  // number of spaces to indent with (at 0, JSON and XML are not indented; YAML, TOML and protobuf use 2)
+    SortKeys    bool // sort map/object keys where the underlying format allows it
+    Minify      bool // collapse output to its most compact representation
+}
+
+// ParseFormatOptions reads the options a client can set via query string:
+// indent=<n>, sort_keys=true|false, minify=true|false.
+func ParseFormatOptions(q url.Values) FormatOptions {
+    opts := FormatOptions{IndentWidth: 2}
+    if v := q.Get("indent"); v != "" {
+        if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+            opts.IndentWidth = n
+        }
+    }
+    if v := q.Get("sort_keys"); v != "" {
+        opts.SortKeys, _ = strconv.ParseBool(v)
+    }
+    if v := q.Get("minify"); v != "" {
+        if b, err := strconv.ParseBool(v); err == nil && b {
+            opts.Minify = true
+            opts.IndentWidth = 0
+        }
+    }
+    return opts
+}
+
+// Formatter reformats data encoded in one particular content type.
+// Implementations should be safe for concurrent use; the registry is
+// shared across all requests.
+type Formatter interface {
+    Format(data []byte, opts FormatOptions) ([]byte, error)
+}
+
+// FormatterFunc adapts a plain function to the Formatter interface.
+type FormatterFunc func(data []byte, opts FormatOptions) ([]byte, error)
+
+func (f FormatterFunc) Format(data []byte, opts FormatOptions) ([]byte, error) {
+    return f(data, opts)
+}
+
+// TreeFormatter is implemented by formatters whose data decodes to a
+// generic tree (map[string]interface{}, []interface{}, scalars) and
+// back, which is what lets formatHandler convert between two such
+// formats via a common intermediate representation instead of only
+// pretty-printing one format in place.
+type TreeFormatter interface {
+    Decode(data []byte) (interface{}, error)
+    Encode(v interface{}, opts FormatOptions) ([]byte, error)
+}
+
+var (
+    registryMu sync.RWMutex
+    registry   = map[string]Formatter{}
+
+    // contentTypes maps a registered formatter name to the MIME type
+    // used on responses for that format, set via RegisterContentType.
+    contentTypes = map[string]string{}
+)
+
+// RegisterFormatter makes a Formatter available under name (matched
+// case-insensitively against the "type" query parameter). Programs
+// importing this package as a library can call RegisterFormatter from
+// an init() to add support for additional content types without
+// touching main.go. Registering under an already-used name replaces it.
+func RegisterFormatter(name string, f Formatter) {
+    registryMu.Lock()
+    defer registryMu.Unlock()
+    registry[strings.ToLower(name)] = f
+}
+
+// RegisterContentType associates a MIME type with a registered
+// formatter name, used to set an accurate Content-Type header on
+// responses and to resolve the Accept header to a formatter name.
+func RegisterContentType(name, mimeType string) {
+    registryMu.Lock()
+    defer registryMu.Unlock()
+    contentTypes[strings.ToLower(name)] = mimeType
+}
+
+// contentTypeFor returns the MIME type registered for name, defaulting
+// to "text/plain; charset=utf-8" for formatters that never called
+// RegisterContentType.
+func contentTypeFor(name string) string {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+    if ct, ok := contentTypes[name]; ok {
+        return ct
+    }
+    return "text/plain; charset=utf-8"
+}
+
+// formatterNameForAccept finds a registered formatter name whose
+// content type matches one of the media types in an Accept header,
+// trying each in order. It returns "" if none match.
+func formatterNameForAccept(accept string) string {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+    for _, media := range strings.Split(accept, ",") {
+        media = strings.TrimSpace(strings.SplitN(media, ";", 2)[0])
+        if media == "" || media == "*/*" {
+            continue
+        }
+        for name, ct := range contentTypes {
+            if strings.HasPrefix(ct, media) {
+                return name
+            }
+        }
+    }
+    return ""
+}
+
+// lookupFormatter returns the formatter registered for name, if any.
+func lookupFormatter(name string) (Formatter, bool) {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+    f, ok := registry[name]
+    return f, ok
+}
+
+// registeredFormatterNames returns the names currently registered, for
+// diagnostics and for the /formats endpoint.
+func registeredFormatterNames() []string {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+    names := make([]string, 0, len(registry))
+    for name := range registry {
+        names = append(names, name)
+    }
+    return names
+}
+
+// errUnsupportedType is returned by formatHandler when "type" does not
+// match any registered formatter.
+func errUnsupportedType(name string) error {
+    return fmt.Errorf("unsupported 'type' parameter %q; registered types: %v", name, registeredFormatterNames())
+}
+
+// lookupTreeFormatter returns the formatter registered for name if it
+// also implements TreeFormatter, which is what conversion requires on
+// both ends of a from/to pair.
+func lookupTreeFormatter(name string) (TreeFormatter, bool) {
+    f, ok := lookupFormatter(name)
+    if !ok {
+        return nil, false
+    }
+    tf, ok := f.(TreeFormatter)
+    return tf, ok
+}
+
+// conversionPair is one legal (from, to) combination for /formats.
+type conversionPair struct {
+    From string `json:"from"`
+    To   string `json:"to"`
+}
+
+// registeredConversionPairs lists every (from, to) pair formatHandler
+// can currently bridge: every formatter pretty-prints itself, and every
+// pair of TreeFormatters can convert into one another.
+func registeredConversionPairs() []conversionPair {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+
+    var treeNames []string
+    for name, f := range registry {
+        if _, ok := f.(TreeFormatter); ok {
+            treeNames = append(treeNames, name)
+        }
+    }
+
+    var pairs []conversionPair
+    for name := range registry {
+        pairs = append(pairs, conversionPair{From: name, To: name})
+    }
+    for _, from := range treeNames {
+        for _, to := range treeNames {
+            if from != to {
+                pairs = append(pairs, conversionPair{From: from, To: to})
+            }
+        }
+    }
+    return pairs
+}
+
+// formatsResponse is the JSON body returned by FormatsHandler.
+type formatsResponse struct {
+    Types       []string         `json:"types"`
+    Conversions []conversionPair `json:"conversions"`
+}
+
+// FormatsHandler lists every registered formatter and every (from, to)
+// pair formatHandler can bridge, so clients can discover what the
+// "type"/"to" query parameters accept without reading the source.
+func FormatsHandler(w http.ResponseWriter, r *http.Request) {
+    resp := formatsResponse{
+        Types:       registeredFormatterNames(),
+        Conversions: registeredConversionPairs(),
+    }
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    if err := json.NewEncoder(w).Encode(resp); err != nil {
+        http.Error(w, "Failed to encode formats response", http.StatusInternalServerError)
+    }
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "log"
+
+    "golang.org/x/net/html"
+)
+
+func init() {
+    RegisterFormatter("html", FormatterFunc(formatHTML))
+    RegisterContentType("html", "text/html; charset=utf-8")
+}
+
+// formatHTML takes raw HTML bytes and returns formatted HTML bytes.
+// HTML has no stable notion of indent width or key order, so opts is
+// currently unused beyond accepting the same signature as every other
+// formatter.
+func formatHTML(data []byte, opts FormatOptions) ([]byte, error) {
+    doc, err := html.Parse(bytes.NewReader(data))
+    if err != nil {
+        log.Printf("Error parsing HTML: %v", err)
+        return nil, fmt.Errorf("failed to parse HTML: %w", err)
+    }
+
+    buf := getBuffer()
+    defer putBuffer(buf)
+
+    if err := html.Render(buf, doc); err != nil {
+        log.Printf("Error rendering HTML: %v", err)
+        return nil, fmt.Errorf("failed to render HTML: %w", err)
+    }
+
+    formatted := make([]byte, buf.Len())
+    copy(formatted, buf.Bytes())
+    return formatted, nil
+}
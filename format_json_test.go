@@ -0,0 +1,72 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "testing"
+)
+
+// payload1MiB builds a ~1 MiB JSON document for benchmarking formatJSON's
+// allocation behavior under the pooled output buffer.
+func payload1MiB(b *testing.B) []byte {
+    b.Helper()
+    type row struct {
+        ID    int    `json:"id"`
+        Name  string `json:"name"`
+        Email string `json:"email"`
+    }
+    rows := make([]row, 0, 20000)
+    for i := 0; i < 20000; i++ {
+        rows = append(rows, row{ID: i, Name: "user", Email: "user@example.com"})
+    }
+    data, err := json.Marshal(rows)
+    if err != nil {
+        b.Fatalf("failed to build benchmark payload: %v", err)
+    }
+    if len(data) < 1<<20 {
+        b.Fatalf("benchmark payload too small: %d bytes", len(data))
+    }
+    return data
+}
+
+// BenchmarkFormatJSON exercises formatJSON end-to-end at ~1 MiB input to
+// show the buffer pool keeps output-buffer allocations from scaling with
+// request volume.
+func BenchmarkFormatJSON(b *testing.B) {
+    data := payload1MiB(b)
+    opts := FormatOptions{IndentWidth: 2}
+
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := formatJSON(data, opts); err != nil {
+            b.Fatalf("formatJSON: %v", err)
+        }
+    }
+}
+
+// BenchmarkFormatJSON_Minify covers the compact-output path.
+func BenchmarkFormatJSON_Minify(b *testing.B) {
+    data := payload1MiB(b)
+    opts := FormatOptions{Minify: true}
+
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := formatJSON(data, opts); err != nil {
+            b.Fatalf("formatJSON: %v", err)
+        }
+    }
+}
+
+// BenchmarkBufferPool_GetPut measures the steady-state cost of borrowing
+// and returning a buffer, independent of any particular formatter.
+func BenchmarkBufferPool_GetPut(b *testing.B) {
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        buf := getBuffer()
+        buf.Write(bytes.Repeat([]byte("x"), 1<<20))
+        putBuffer(buf)
+    }
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+    "strings"
+)
+
+func init() {
+    RegisterFormatter("protobuf", FormatterFunc(formatProtobuf))
+    RegisterFormatter("proto", FormatterFunc(formatProtobuf))
+    RegisterContentType("protobuf", "text/plain; charset=utf-8")
+    RegisterContentType("proto", "text/plain; charset=utf-8")
+}
+
+// formatProtobuf re-indents protobuf text-format input. Text-format has
+// no accompanying schema in this request path (the caller only posts
+// bytes + a "type" query param, not a .proto descriptor), so rather than
+// round-tripping through a real proto.Message this walks the brace
+// structure directly, the same way formatSQL works off of keywords
+// rather than a full grammar.
+func formatProtobuf(data []byte, opts FormatOptions) ([]byte, error) {
+    indentWidth := opts.IndentWidth
+    if indentWidth <= 0 {
+        indentWidth = 2
+    }
+    unit := strings.Repeat(" ", indentWidth)
+
+    var out strings.Builder
+    depth := 0
+    lines := strings.Split(strings.ReplaceAll(string(data), "{", "{\n"), "}")
+    for li, chunk := range lines {
+        for _, rawLine := range strings.Split(chunk, "\n") {
+            line := strings.TrimSpace(rawLine)
+            if line == "" {
+                continue
+            }
+            if opts.Minify {
+                out.WriteString(line)
+                out.WriteByte(' ')
+                continue
+            }
+            out.WriteString(strings.Repeat(unit, depth))
+            out.WriteString(line)
+            out.WriteByte('\n')
+            if strings.HasSuffix(line, "{") {
+                depth++
+            }
+        }
+        if li != len(lines)-1 {
+            if depth > 0 {
+                depth--
+            }
+            if opts.Minify {
+                out.WriteString("} ")
+            } else {
+                out.WriteString(strings.Repeat(unit, depth))
+                out.WriteString("}\n")
+            }
+        }
+    }
+    return []byte(strings.TrimSpace(out.String()) + "\n"), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+    "regexp"
+    "strings"
+)
+
+func init() {
+    RegisterFormatter("sql", FormatterFunc(formatSQL))
+    RegisterContentType("sql", "application/sql")
+}
+
+// sqlKeywords lists the keywords formatSQL upper-cases. It is not an
+// exhaustive SQL grammar, just enough to make hand-pasted queries read
+// consistently; formatSQL does not attempt to validate the statement.
+var sqlKeywords = []string{
+    "select", "from", "where", "insert", "into", "values", "update", "set",
+    "delete", "join", "inner", "outer", "left", "right", "on", "group",
+    "by", "order", "having", "limit", "offset", "and", "or", "not", "null",
+    "is", "in", "as", "distinct", "union", "all", "create", "table",
+    "alter", "drop", "index", "primary", "key", "foreign", "references",
+    "default", "asc", "desc", "case", "when", "then", "else", "end",
+}
+
+var sqlKeywordPattern = buildSQLKeywordPattern()
+
+func buildSQLKeywordPattern() *regexp.Regexp {
+    escaped := make([]string, len(sqlKeywords))
+    for i, kw := range sqlKeywords {
+        escaped[i] = regexp.QuoteMeta(kw)
+    }
+    return regexp.MustCompile(`(?i)\b(` + strings.Join(escaped, "|") + `)\b`)
+}
+
+// formatSQL normalizes whitespace and upper-cases known SQL keywords.
+// opts.Minify collapses the statement onto a single line; otherwise each
+// top-level clause keyword starts a new line.
+func formatSQL(data []byte, opts FormatOptions) ([]byte, error) {
+    collapsed := strings.Join(strings.Fields(string(data)), " ")
+    upper := sqlKeywordPattern.ReplaceAllStringFunc(collapsed, strings.ToUpper)
+
+    if opts.Minify {
+        return []byte(upper), nil
+    }
+
+    clauseStarts := []string{
+        "SELECT", "FROM", "WHERE", "GROUP BY", "ORDER BY", "HAVING",
+        "LIMIT", "INSERT INTO", "VALUES", "UPDATE", "SET", "DELETE FROM",
+        "JOIN", "INNER JOIN", "LEFT JOIN", "RIGHT JOIN", "OUTER JOIN",
+        "UNION",
+    }
+    // One pass over all clauses, so a multi-word clause such as
+    // "INNER JOIN" is matched whole instead of being split before "JOIN".
+    clausePattern := regexp.MustCompile(` (` + strings.Join(clauseStarts, "|") + `)\b`)
+    upper = clausePattern.ReplaceAllString(upper, "\n$1")
+    return []byte(strings.TrimLeft(upper, "\n")), nil
+}
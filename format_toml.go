@@ -0,0 +1,74 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "log"
+    "strings"
+
+    "github.com/BurntSushi/toml"
+)
+
+func init() {
+    RegisterFormatter("toml", tomlFormatter{})
+    RegisterContentType("toml", "application/toml")
+}
+
+// tomlFormatter implements both Formatter and TreeFormatter for TOML.
+// TOML documents are always top-level tables, so the generic tree it
+// decodes to and encodes from is always a map[string]interface{}.
+type tomlFormatter struct{}
+
+func (tomlFormatter) Format(data []byte, opts FormatOptions) ([]byte, error) {
+    return formatTOML(data, opts)
+}
+
+// Decode parses TOML bytes into the generic tree used for cross-format
+// conversion.
+func (tomlFormatter) Decode(data []byte) (interface{}, error) {
+    var parsed map[string]interface{}
+    if err := toml.Unmarshal(data, &parsed); err != nil {
+        return nil, fmt.Errorf("failed to parse TOML: %w", err)
+    }
+    return parsed, nil
+}
+
+// Encode serializes a generic tree as TOML. Since TOML has no bare
+// top-level scalar or array, v must be (or decode-assert to) a
+// map[string]interface{}.
+func (tomlFormatter) Encode(v interface{}, opts FormatOptions) ([]byte, error) {
+    table, ok := v.(map[string]interface{})
+    if !ok {
+        return nil, fmt.Errorf("cannot encode as TOML: top-level value must be an object, got %T", v)
+    }
+    if opts.SortKeys {
+        table = sortJSONKeys(table).(map[string]interface{})
+    }
+
+    var buf bytes.Buffer
+    enc := toml.NewEncoder(&buf)
+    indent := opts.IndentWidth
+    if indent <= 0 {
+        indent = 2
+    }
+    enc.Indent = strings.Repeat(" ", indent)
+    if err := enc.Encode(table); err != nil {
+        return nil, fmt.Errorf("failed to format TOML: %w", err)
+    }
+    return buf.Bytes(), nil
+}
+
+// formatTOML takes raw TOML bytes and returns re-serialized TOML bytes.
+func formatTOML(data []byte, opts FormatOptions) ([]byte, error) {
+    parsed, err := (tomlFormatter{}).Decode(data)
+    if err != nil {
+        log.Printf("Error parsing TOML: %v", err)
+        return nil, err
+    }
+    formatted, err := (tomlFormatter{}).Encode(parsed, opts)
+    if err != nil {
+        log.Printf("Error formatting TOML: %v", err)
+        return nil, err
+    }
+    return formatted, nil
+}
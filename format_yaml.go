@@ -0,0 +1,95 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "log"
+
+    "gopkg.in/yaml.v3"
+)
+
+func init() {
+    RegisterFormatter("yaml", yamlFormatter{})
+    RegisterFormatter("yml", yamlFormatter{})
+    RegisterContentType("yaml", "application/yaml")
+    RegisterContentType("yml", "application/yaml")
+}
+
+// yamlFormatter implements both Formatter and TreeFormatter for YAML.
+type yamlFormatter struct{}
+
+func (yamlFormatter) Format(data []byte, opts FormatOptions) ([]byte, error) {
+    return formatYAML(data, opts)
+}
+
+// Decode parses YAML bytes into the generic tree used for cross-format
+// conversion.
+func (yamlFormatter) Decode(data []byte) (interface{}, error) {
+    var parsed interface{}
+    if err := yaml.Unmarshal(data, &parsed); err != nil {
+        return nil, fmt.Errorf("failed to parse YAML: %w", err)
+    }
+    return normalizeYAMLMaps(parsed), nil
+}
+
+// Encode serializes a generic tree (as produced by any TreeFormatter's
+// Decode) as YAML.
+func (yamlFormatter) Encode(v interface{}, opts FormatOptions) ([]byte, error) {
+    if opts.SortKeys {
+        v = sortJSONKeys(v)
+    }
+
+    indent := opts.IndentWidth
+    if indent <= 0 {
+        indent = 2
+    }
+
+    var buf bytes.Buffer
+    enc := yaml.NewEncoder(&buf)
+    enc.SetIndent(indent)
+    if err := enc.Encode(v); err != nil {
+        return nil, fmt.Errorf("failed to format YAML: %w", err)
+    }
+    if err := enc.Close(); err != nil {
+        return nil, fmt.Errorf("failed to format YAML: %w", err)
+    }
+    return buf.Bytes(), nil
+}
+
+// formatYAML takes raw YAML bytes and returns re-indented YAML bytes.
+func formatYAML(data []byte, opts FormatOptions) ([]byte, error) {
+    parsed, err := (yamlFormatter{}).Decode(data)
+    if err != nil {
+        log.Printf("Error parsing YAML: %v", err)
+        return nil, err
+    }
+    formatted, err := (yamlFormatter{}).Encode(parsed, opts)
+    if err != nil {
+        log.Printf("Error formatting YAML: %v", err)
+        return nil, err
+    }
+    return formatted, nil
+}
+
+// normalizeYAMLMaps converts the map[string]interface{} values yaml.v3
+// decodes into, recursively, so sortJSONKeys and the cross-format
+// conversion in formatHandler can treat YAML and JSON documents
+// identically.
+func normalizeYAMLMaps(v interface{}) interface{} {
+    switch val := v.(type) {
+    case map[string]interface{}:
+        out := make(map[string]interface{}, len(val))
+        for k, item := range val {
+            out[k] = normalizeYAMLMaps(item)
+        }
+        return out
+    case []interface{}:
+        out := make([]interface{}, len(val))
+        for i, item := range val {
+            out[i] = normalizeYAMLMaps(item)
+        }
+        return out
+    default:
+        return v
+    }
+}
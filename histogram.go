@@ -0,0 +1,69 @@
+package main
+
+import "sync/atomic"
+
+// histogram is a fixed-bucket cumulative histogram updated with
+// atomic.AddInt64, so Observe never takes a lock. Each bucket counts
+// every observation less than or equal to its bound, matching
+// Prometheus's cumulative "le" bucket semantics. Observations above the
+// last bound fall in no bucket and are counted only in total, which is
+// what the exported "+Inf" bucket reports.
+type histogram struct {
+    bounds []int64 // upper bound per bucket, ascending
+    counts []int64 // counts[i] = observations <= bounds[i], atomic
+    sum    int64   // atomic running sum of observed values
+    total  int64   // atomic running count of observations
+}
+
+func newHistogram(bounds []int64) *histogram {
+    return &histogram{
+        bounds: bounds,
+        counts: make([]int64, len(bounds)),
+    }
+}
+
+// Observe records v, incrementing every bucket whose bound is >= v.
+func (h *histogram) Observe(v int64) {
+    atomic.AddInt64(&h.sum, v)
+    atomic.AddInt64(&h.total, 1)
+    for i, bound := range h.bounds {
+        if v <= bound {
+            atomic.AddInt64(&h.counts[i], 1)
+        }
+    }
+}
+
+// snapshot is a point-in-time, non-atomic read of a histogram's state
+// for exposition (JSON or Prometheus text).
+type histogramSnapshot struct {
+    Bounds []int64 `json:"bounds"`
+    Counts []int64 `json:"counts"`
+    Sum    int64   `json:"sum"`
+    Total  int64   `json:"total"`
+}
+
+func (h *histogram) snapshot() histogramSnapshot {
+    counts := make([]int64, len(h.counts))
+    for i := range h.counts {
+        counts[i] = atomic.LoadInt64(&h.counts[i])
+    }
+    return histogramSnapshot{
+        Bounds: h.bounds,
+        Counts: counts,
+        Sum:    atomic.LoadInt64(&h.sum),
+        Total:  atomic.LoadInt64(&h.total),
+    }
+}
+
+// durationBucketsMicros are request-duration bucket bounds in
+// microseconds, from 1ms up to 10s.
+var durationBucketsMicros = []int64{
+    1_000, 5_000, 10_000, 25_000, 50_000, 100_000,
+    250_000, 500_000, 1_000_000, 2_500_000, 5_000_000, 10_000_000,
+}
+
+// payloadBucketsBytes are payload-size bucket bounds in bytes, from
+// 100 bytes up to 100 MB.
+var payloadBucketsBytes = []int64{
+    100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000, 100_000_000,
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+    "sync"
+    "testing"
+)
+
+func TestHistogramObserve(t *testing.T) {
+    h := newHistogram([]int64{10, 100, 1000})
+
+    h.Observe(5)
+    h.Observe(50)
+    h.Observe(500)
+    h.Observe(5000)
+
+    snap := h.snapshot()
+    want := []int64{1, 2, 3} // <=10: {5}; <=100: {5,50}; <=1000: {5,50,500}
+    for i, w := range want {
+        if snap.Counts[i] != w {
+            t.Errorf("bucket %d (<=%d) = %d, want %d", i, snap.Bounds[i], snap.Counts[i], w)
+        }
+    }
+    if snap.Total != 4 {
+        t.Errorf("Total = %d, want 4", snap.Total)
+    }
+    if snap.Sum != 5+50+500+5000 {
+        t.Errorf("Sum = %d, want %d", snap.Sum, 5+50+500+5000)
+    }
+}
+
+// TestHistogramObserve_Concurrent exercises Observe from many goroutines
+// at once. It exists to catch races in the atomic bucket/sum/total
+// updates; run with -race to verify there's no concealed shared-state
+// mutation outside the atomic ops.
+func TestHistogramObserve_Concurrent(t *testing.T) {
+    h := newHistogram(durationBucketsMicros)
+
+    const goroutines = 50
+    const perGoroutine = 200
+
+    var wg sync.WaitGroup
+    wg.Add(goroutines)
+    for i := 0; i < goroutines; i++ {
+        go func() {
+            defer wg.Done()
+            for j := 0; j < perGoroutine; j++ {
+                h.Observe(1_000)
+            }
+        }()
+    }
+    wg.Wait()
+
+    snap := h.snapshot()
+    wantTotal := int64(goroutines * perGoroutine)
+    if snap.Total != wantTotal {
+        t.Errorf("Total = %d, want %d", snap.Total, wantTotal)
+    }
+    if snap.Sum != wantTotal*1_000 {
+        t.Errorf("Sum = %d, want %d", snap.Sum, wantTotal*1_000)
+    }
+    if snap.Counts[0] != wantTotal {
+        t.Errorf("bucket[0] (<=%d) = %d, want %d", snap.Bounds[0], snap.Counts[0], wantTotal)
+    }
+}
+
+// TestRecordFormatRequest_Concurrent drives recordFormatRequest from
+// many goroutines for a type/subject pair unique to this test, and
+// checks the resulting counters add up exactly. getTypeStats and
+// getSubjectStats both lazily create their entry via LoadOrStore, which
+// is the part most likely to race under concurrent first-use.
+func TestRecordFormatRequest_Concurrent(t *testing.T) {
+    const typ = "histogram_test_type"
+    const sub = "histogram_test_subject"
+    const goroutines = 50
+    const perGoroutine = 20
+
+    var wg sync.WaitGroup
+    wg.Add(goroutines)
+    for i := 0; i < goroutines; i++ {
+        go func() {
+            defer wg.Done()
+            for j := 0; j < perGoroutine; j++ {
+                recordFormatRequest(typ, sub, statusOK, 100, 10)
+            }
+        }()
+    }
+    wg.Wait()
+
+    want := int64(goroutines * perGoroutine)
+    ts := getTypeStats(typ)
+    if got := ts.counts[statusOK]; got != want {
+        t.Errorf("typeStats counts[ok] = %d, want %d", got, want)
+    }
+    if got := ts.duration.snapshot().Total; got != want {
+        t.Errorf("typeStats duration total = %d, want %d", got, want)
+    }
+
+    ss := getSubjectStats(sub)
+    if got := ss.requestCount; got != want {
+        t.Errorf("subjectStats requestCount = %d, want %d", got, want)
+    }
+}
@@ -2,32 +2,17 @@ package main
 
 import (
     "bytes"
-    "encoding/json"
-    "encoding/xml"
     "fmt"
     "io/ioutil"
     "log"
     "net/http"
     "os"
     "strings"
-    "sync"
     "time"
 
     "github.com/gorilla/mux"
-    "golang.org/x/net/html"
 )
 
-// Metrics struct to track /format API usage
-type FormatAPIMetrics struct {
-    RequestCount    int64         // Total number of requests
-    ErrorCount      int64         // Total number of errors
-    TotalDuration   time.Duration // Total time spent processing requests
-    MaxPayloadSize  int64         // Largest payload size received
-    mu              sync.Mutex    // Mutex for thread-safe updates
-}
-
-var formatMetrics = &FormatAPIMetrics{}
-
 // Logger middleware to log requests
 func Logger(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -45,165 +30,171 @@ func Logger(next http.Handler) http.Handler {
     })
 }
 
-// Middleware to track metrics for /format API
+// FormatMetricsMiddleware records per-type, per-status request metrics
+// around /format. It reads the request upfront to measure payload size;
+// streaming requests (?stream=true) skip that pre-read, since the whole
+// point of streaming is to never hold the body in memory at once.
 func FormatMetricsMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         start := time.Now()
-
-        // Read the request body to calculate payload size
-        body, err := ioutil.ReadAll(r.Body)
-        if err != nil {
-            http.Error(w, "Failed to read request body", http.StatusBadRequest)
-            return
+        contentType := strings.ToLower(r.URL.Query().Get("type"))
+        if contentType == "" {
+            contentType = strings.ToLower(r.URL.Query().Get("from"))
+        }
+        sub := subjectFromContext(r.Context())
+
+        var payloadSize int64
+        if r.URL.Query().Get("stream") != "true" {
+            r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+            body, err := ioutil.ReadAll(r.Body)
+            if err != nil {
+                if isBodyTooLargeErr(err) {
+                    http.Error(w, fmt.Sprintf("Request body exceeds the %d byte limit", maxBodyBytes), http.StatusRequestEntityTooLarge)
+                } else {
+                    http.Error(w, "Failed to read request body", http.StatusBadRequest)
+                }
+                recordFormatRequest(contentType, sub, statusParseError, time.Since(start).Microseconds(), 0)
+                return
+            }
+            r.Body = ioutil.NopCloser(bytes.NewBuffer(body)) // Restore the body for the next handler
+            payloadSize = int64(len(body))
         }
-        r.Body = ioutil.NopCloser(bytes.NewBuffer(body)) // Restore the body for the next handler
-
-        payloadSize := int64(len(body))
-
-        // Call the next handler
-        next.ServeHTTP(w, r)
 
-        duration := time.Since(start)
+        rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+        next.ServeHTTP(rec, r)
 
-        // Update metrics
-        formatMetrics.mu.Lock()
-        defer formatMetrics.mu.Unlock()
-        formatMetrics.RequestCount++
-        formatMetrics.TotalDuration += duration
-        if payloadSize > formatMetrics.MaxPayloadSize {
-            formatMetrics.MaxPayloadSize = payloadSize
-        }
+        recordFormatRequest(contentType, sub, classifyHTTPStatus(rec.status), time.Since(start).Microseconds(), payloadSize)
     })
 }
 
-// formatJSON takes raw JSON bytes and returns formatted JSON bytes
-func formatJSON(data []byte) ([]byte, error) {
-    var parsedJSON interface{}
-    if err := json.Unmarshal(data, &parsedJSON); err != nil {
-        log.Printf("Error parsing JSON: %v", err)
-        return nil, fmt.Errorf("failed to parse JSON: %w", err)
+// formatHandler handles the formatting logic based on the content type,
+// dispatching to whichever Formatter is registered for it. If "to" is
+// set to a different registered type than "from"/"type", it converts
+// between the two via their shared TreeFormatter representation instead
+// of just pretty-printing in place.
+func formatHandler(w http.ResponseWriter, r *http.Request) {
+    // Extract the content type from query parameters; "from" is accepted
+    // in place of "type".
+    contentType := strings.ToLower(r.URL.Query().Get("type"))
+    if contentType == "" {
+        contentType = strings.ToLower(r.URL.Query().Get("from"))
     }
-    formattedJSON, err := json.MarshalIndent(parsedJSON, "", "  ")
-    if err != nil {
-        log.Printf("Error formatting JSON: %v", err)
-        return nil, fmt.Errorf("failed to format JSON: %w", err)
+    if contentType == "" {
+        log.Println("Missing 'type' parameter in request")
+        http.Error(w, fmt.Sprintf("Missing 'type' parameter. Registered types: %v.", registeredFormatterNames()), http.StatusBadRequest)
+        return
     }
-    return formattedJSON, nil
-}
 
-// formatXML takes raw XML bytes and returns formatted XML bytes
-func formatXML(data []byte) ([]byte, error) {
-    var parsedXML interface{}
-    if err := xml.Unmarshal(data, &parsedXML); err != nil {
-        log.Printf("Error parsing XML: %v", err)
-        return nil, fmt.Errorf("failed to parse XML: %w", err)
-    }
-    formattedXML, err := xml.MarshalIndent(parsedXML, "", "  ")
-    if err != nil {
-        log.Printf("Error formatting XML: %v", err)
-        return nil, fmt.Errorf("failed to format XML: %w", err)
+    formatter, ok := lookupFormatter(contentType)
+    if !ok {
+        log.Printf("Invalid 'type' parameter: %s", contentType)
+        http.Error(w, errUnsupportedType(contentType).Error(), http.StatusBadRequest)
+        return
     }
-    return append(formattedXML, '\n'), nil
-}
 
-// formatHTML takes raw HTML bytes and returns formatted HTML bytes
-func formatHTML(data []byte) ([]byte, error) {
-    doc, err := html.Parse(bytes.NewReader(data))
-    if err != nil {
-        log.Printf("Error parsing HTML: %v", err)
-        return nil, fmt.Errorf("failed to parse HTML: %w", err)
+    // "to" picks a target format for conversion. If it isn't set
+    // explicitly, fall back to the Accept header, and finally to "type"
+    // itself (pretty-print in place, the original behavior).
+    toType := strings.ToLower(r.URL.Query().Get("to"))
+    if toType == "" {
+        toType = formatterNameForAccept(r.Header.Get("Accept"))
     }
-    var buf bytes.Buffer
-    if err := html.Render(&buf, doc); err != nil {
-        log.Printf("Error rendering HTML: %v", err)
-        return nil, fmt.Errorf("failed to render HTML: %w", err)
+    if toType == "" {
+        toType = contentType
     }
-    return buf.Bytes(), nil
-}
 
-// formatHandler handles the formatting logic based on the content type
-func formatHandler(w http.ResponseWriter, r *http.Request) {
-    // Extract the content type from query parameters
-    contentType := strings.ToLower(r.URL.Query().Get("type"))
-    if contentType == "" {
-        log.Println("Missing 'type' parameter in request")
-        http.Error(w, "Missing 'type' parameter. Specify 'json', 'xml', or 'html'.", http.StatusBadRequest)
-        formatMetrics.mu.Lock()
-        formatMetrics.ErrorCount++
-        formatMetrics.mu.Unlock()
+    opts := ParseFormatOptions(r.URL.Query())
+
+    if r.URL.Query().Get("stream") == "true" {
+        // Streaming mode reformats the body incrementally instead of
+        // buffering the whole result, so skip the MaxBytesReader cap
+        // meant for the buffered path and rely on the server's
+        // WriteTimeout (via streamGuard) to bound how long a request
+        // can run instead. Streaming only supports pretty-printing in
+        // place; cross-format conversion requires buffering the decoded
+        // tree anyway, which defeats the point of streaming.
+        streamFormatHandler(w, r, formatter, contentType, opts, serverWriteTimeout)
         return
     }
 
-    // Read the raw input data from the request body
+    // Read the raw input data from the request body, bounded so a large
+    // or malicious payload cannot exhaust server memory.
+    r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
     body, err := ioutil.ReadAll(r.Body)
     defer r.Body.Close()
     if err != nil {
+        if isBodyTooLargeErr(err) {
+            log.Printf("Request body exceeded %d byte limit", maxBodyBytes)
+            http.Error(w, fmt.Sprintf("Request body exceeds the %d byte limit", maxBodyBytes), http.StatusRequestEntityTooLarge)
+            return
+        }
         log.Printf("Error reading request body: %v", err)
         http.Error(w, "Failed to read request body", http.StatusBadRequest)
-        formatMetrics.mu.Lock()
-        formatMetrics.ErrorCount++
-        formatMetrics.mu.Unlock()
         return
     }
 
     // Log the received request data
     log.Printf("Received request with type=%s and body=%s", contentType, string(body))
 
-    // Format the content based on the specified type
     var formattedData []byte
-    switch contentType {
-    case "json":
-        formattedData, err = formatJSON(body)
-    case "xml":
-        formattedData, err = formatXML(body)
-    case "html":
-        formattedData, err = formatHTML(body)
-    default:
-        log.Printf("Invalid 'type' parameter: %s", contentType)
-        http.Error(w, "Invalid 'type' parameter. Supported types are 'json', 'xml', and 'html'.", http.StatusBadRequest)
-        formatMetrics.mu.Lock()
-        formatMetrics.ErrorCount++
-        formatMetrics.mu.Unlock()
-        return
+    if toType == contentType {
+        formattedData, err = formatter.Format(body, opts)
+    } else {
+        formattedData, err = convertFormat(contentType, toType, body, opts)
     }
-
     if err != nil {
+        if _, ok := err.(*unbridgeableFormatsError); ok {
+            log.Printf("Cannot convert %s to %s: %v", contentType, toType, err)
+            http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+            return
+        }
         log.Printf("Formatting failed: %v", err)
         http.Error(w, fmt.Sprintf("Formatting failed: %s", err.Error()), http.StatusInternalServerError)
-        formatMetrics.mu.Lock()
-        formatMetrics.ErrorCount++
-        formatMetrics.mu.Unlock()
         return
     }
 
     // Set the response headers and write the formatted data
-    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    w.Header().Set("Content-Type", contentTypeFor(toType))
     w.WriteHeader(http.StatusOK)
     w.Write(formattedData)
 
     // Log the successful response
-    log.Printf("Formatted data successfully returned for type=%s", contentType)
+    log.Printf("Formatted data successfully returned for type=%s, to=%s", contentType, toType)
+}
+
+// unbridgeableFormatsError is returned by convertFormat when either side
+// of a from/to pair cannot be decoded or encoded to the generic tree
+// representation, e.g. converting HTML to JSON.
+type unbridgeableFormatsError struct {
+    from, to string
 }
 
-// MetricsHandler exposes metrics for the /format API
-func MetricsHandler(w http.ResponseWriter, r *http.Request) {
-    formatMetrics.mu.Lock()
-    defer formatMetrics.mu.Unlock()
+func (e *unbridgeableFormatsError) Error() string {
+    return fmt.Sprintf("cannot convert %q to %q: one or both formats do not support cross-format conversion", e.from, e.to)
+}
 
-    averageDuration := time.Duration(0)
-    if formatMetrics.RequestCount > 0 {
-        averageDuration = formatMetrics.TotalDuration / time.Duration(formatMetrics.RequestCount)
+// convertFormat bridges two registered formats through their shared
+// generic tree representation: decode "from" to a tree, then encode
+// that tree as "to". Both sides must implement TreeFormatter.
+func convertFormat(from, to string, body []byte, opts FormatOptions) ([]byte, error) {
+    fromFormatter, ok := lookupTreeFormatter(from)
+    if !ok {
+        return nil, &unbridgeableFormatsError{from: from, to: to}
+    }
+    toFormatter, ok := lookupTreeFormatter(to)
+    if !ok {
+        return nil, &unbridgeableFormatsError{from: from, to: to}
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(map[string]interface{}{
-        "request_count":          formatMetrics.RequestCount,
-        "error_count":            formatMetrics.ErrorCount,
-        "total_duration_ms":      formatMetrics.TotalDuration.Milliseconds(),
-        "average_duration_ms":    averageDuration.Milliseconds(),
-        "max_payload_size_bytes": formatMetrics.MaxPayloadSize,
-    })
+    tree, err := fromFormatter.Decode(body)
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse %s: %w", from, err)
+    }
+    out, err := toFormatter.Encode(tree, opts)
+    if err != nil {
+        return nil, fmt.Errorf("failed to render as %s: %w", to, err)
+    }
+    return out, nil
 }
 
 func main() {
@@ -219,15 +210,30 @@ func main() {
     // Add logging middleware
     r.Use(Logger)
 
-    // Define the endpoint for formatting with metrics middleware
-    r.HandleFunc("/format", formatHandler).Methods("POST").Handler(FormatMetricsMiddleware(http.HandlerFunc(formatHandler)))
+    // Define the endpoint for formatting with metrics middleware. When
+    // JWT_PUBKEY is configured, JWTAuthMiddleware also protects it.
+    r.Handle("/format", JWTAuthMiddleware(FormatMetricsMiddleware(http.HandlerFunc(formatHandler)))).Methods("POST")
 
     // Define the metrics endpoint
-    r.HandleFunc("/metrics", MetricsHandler).Methods("GET")
+    r.Handle("/metrics", JWTAuthMiddleware(http.HandlerFunc(MetricsHandler))).Methods("GET")
+
+    // Define the formats discovery endpoint, unauthenticated since it
+    // exposes no request data, only the server's own capabilities.
+    r.HandleFunc("/formats", FormatsHandler).Methods("GET")
+
+    // Start the HTTP server with explicit timeouts; the zero-value
+    // defaults from http.ListenAndServe leave slow or stalled
+    // connections open indefinitely.
+    server := &http.Server{
+        Addr:         ":" + port,
+        Handler:      r,
+        ReadTimeout:  serverReadTimeout,
+        WriteTimeout: serverWriteTimeout,
+        IdleTimeout:  serverIdleTimeout,
+    }
 
-    // Start the HTTP server
     log.Printf("Server is running on http://localhost:%s", port)
-    if err := http.ListenAndServe(":"+port, r); err != nil {
+    if err := server.ListenAndServe(); err != nil {
         log.Fatalf("Server failed to start: %s", err)
     }
 }
\ No newline at end of file
@@ -0,0 +1,66 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// withTestMaxBodyBytes swaps maxBodyBytes for the duration of a test and
+// restores it afterward, so tests don't depend on MAX_BODY_BYTES being
+// set in the environment.
+func withTestMaxBodyBytes(t *testing.T, n int64) {
+    t.Helper()
+    orig := maxBodyBytes
+    maxBodyBytes = n
+    t.Cleanup(func() { maxBodyBytes = orig })
+}
+
+func TestFormatHandler_BodyTooLarge(t *testing.T) {
+    withTestMaxBodyBytes(t, 10)
+
+    req := httptest.NewRequest("POST", "/format?type=json", strings.NewReader(`{"this body":"is over ten bytes"}`))
+    rec := httptest.NewRecorder()
+
+    formatHandler(rec, req)
+
+    if rec.Code != http.StatusRequestEntityTooLarge {
+        t.Fatalf("status = %d, body = %q, want %d", rec.Code, rec.Body.String(), http.StatusRequestEntityTooLarge)
+    }
+}
+
+func TestFormatHandler_BodyWithinLimit(t *testing.T) {
+    withTestMaxBodyBytes(t, 1<<20)
+
+    req := httptest.NewRequest("POST", "/format?type=json", strings.NewReader(`{"a":1}`))
+    rec := httptest.NewRecorder()
+
+    formatHandler(rec, req)
+
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d, body = %q, want %d", rec.Code, rec.Body.String(), http.StatusOK)
+    }
+}
+
+func TestFormatMetricsMiddleware_BodyTooLarge(t *testing.T) {
+    withTestMaxBodyBytes(t, 10)
+
+    called := false
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        called = true
+        w.WriteHeader(http.StatusOK)
+    })
+
+    req := httptest.NewRequest("POST", "/format?type=json", strings.NewReader(`{"this body":"is over ten bytes"}`))
+    rec := httptest.NewRecorder()
+
+    FormatMetricsMiddleware(next).ServeHTTP(rec, req)
+
+    if rec.Code != http.StatusRequestEntityTooLarge {
+        t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+    }
+    if called {
+        t.Error("next handler should not be called when the body exceeds the limit")
+    }
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+)
+
+// formatStatus classifies how a /format request was resolved, used as
+// the "status" label on per-type metrics.
+type formatStatus int
+
+const (
+    statusOK formatStatus = iota
+    statusParseError
+    statusRenderError
+    numFormatStatuses
+)
+
+func (s formatStatus) String() string {
+    switch s {
+    case statusOK:
+        return "ok"
+    case statusParseError:
+        return "parse_error"
+    case statusRenderError:
+        return "render_error"
+    default:
+        return "unknown"
+    }
+}
+
+// classifyHTTPStatus maps the HTTP status code formatHandler actually
+// wrote to a formatStatus, so metrics recording doesn't need formatHandler
+// to separately report what went wrong.
+func classifyHTTPStatus(code int) formatStatus {
+    switch {
+    case code >= 200 && code < 300:
+        return statusOK
+    case code == http.StatusInternalServerError:
+        return statusRenderError
+    default:
+        return statusParseError
+    }
+}
+
+// typeStats holds counters and histograms for one "type" label
+// (json/xml/yaml/...). All fields are updated without a lock: counts
+// via atomic.AddInt64, duration/payload via the lock-free histogram.
+type typeStats struct {
+    counts   [numFormatStatuses]int64
+    duration *histogram
+    payload  *histogram
+}
+
+func newTypeStats() *typeStats {
+    return &typeStats{
+        duration: newHistogram(durationBucketsMicros),
+        payload:  newHistogram(payloadBucketsBytes),
+    }
+}
+
+var typeStatsMap sync.Map // string -> *typeStats
+
+// getTypeStats returns the typeStats for typ, creating it on first use.
+// The map write only happens once per distinct type ever seen, not on
+// every request, so the hot path is a sync.Map.Load.
+func getTypeStats(typ string) *typeStats {
+    if v, ok := typeStatsMap.Load(typ); ok {
+        return v.(*typeStats)
+    }
+    actual, _ := typeStatsMap.LoadOrStore(typ, newTypeStats())
+    return actual.(*typeStats)
+}
+
+// subjectStats tracks per-caller usage, keyed by JWT "sub" claim (or
+// anonymousSubject when JWT auth isn't configured for the route). The
+// counters are atomic so recording a request never blocks on other
+// callers' requests.
+type subjectStats struct {
+    requestCount int64
+    errorCount   int64
+}
+
+var subjectStatsMap sync.Map // string -> *subjectStats
+
+func getSubjectStats(sub string) *subjectStats {
+    if v, ok := subjectStatsMap.Load(sub); ok {
+        return v.(*subjectStats)
+    }
+    actual, _ := subjectStatsMap.LoadOrStore(sub, &subjectStats{})
+    return actual.(*subjectStats)
+}
+
+// Global counters, atomic so no request ever takes a lock to update them.
+var (
+    totalRequestCount int64
+    totalErrorCount   int64
+    authFailureCount  int64
+    maxPayloadSize    int64
+)
+
+func recordMaxPayloadSize(n int64) {
+    for {
+        old := atomic.LoadInt64(&maxPayloadSize)
+        if n <= old {
+            return
+        }
+        if atomic.CompareAndSwapInt64(&maxPayloadSize, old, n) {
+            return
+        }
+    }
+}
+
+func recordAuthFailure() {
+    atomic.AddInt64(&authFailureCount, 1)
+}
+
+// recordFormatRequest is the single place that updates every metric for
+// one /format request: global counters, the per-type histogram/status
+// counts, and the calling subject's counters.
+func recordFormatRequest(typ, sub string, status formatStatus, durationMicros, payloadBytes int64) {
+    atomic.AddInt64(&totalRequestCount, 1)
+    if status != statusOK {
+        atomic.AddInt64(&totalErrorCount, 1)
+    }
+    recordMaxPayloadSize(payloadBytes)
+
+    ts := getTypeStats(typ)
+    atomic.AddInt64(&ts.counts[status], 1)
+    ts.duration.Observe(durationMicros)
+    ts.payload.Observe(payloadBytes)
+
+    ss := getSubjectStats(sub)
+    atomic.AddInt64(&ss.requestCount, 1)
+    if status != statusOK {
+        atomic.AddInt64(&ss.errorCount, 1)
+    }
+}
+
+// responseRecorder wraps a ResponseWriter to capture the status code
+// the handler wrote, since FormatMetricsMiddleware needs it after
+// next.ServeHTTP returns but formatHandler otherwise has no way to
+// report it back without reaching into the metrics package itself.
+type responseRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+    rec.status = status
+    rec.ResponseWriter.WriteHeader(status)
+}
+
+// MetricsHandler exposes metrics for the /format API. It returns JSON
+// when the client sends "Accept: application/json" and Prometheus text
+// exposition format otherwise, which is the default so that a
+// Prometheus scrape config pointed at /metrics with no special Accept
+// header works out of the box.
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+    if strings.Contains(r.Header.Get("Accept"), "application/json") {
+        writeMetricsJSON(w)
+        return
+    }
+    writeMetricsPrometheus(w)
+}
+
+func writeMetricsJSON(w http.ResponseWriter) {
+    types := map[string]interface{}{}
+    typeStatsMap.Range(func(key, value interface{}) bool {
+        ts := value.(*typeStats)
+        counts := map[string]int64{}
+        for s := formatStatus(0); s < numFormatStatuses; s++ {
+            counts[s.String()] = atomic.LoadInt64(&ts.counts[s])
+        }
+        types[key.(string)] = map[string]interface{}{
+            "counts":             counts,
+            "duration_micros":    ts.duration.snapshot(),
+            "payload_bytes":      ts.payload.snapshot(),
+        }
+        return true
+    })
+
+    subjects := map[string]interface{}{}
+    subjectStatsMap.Range(func(key, value interface{}) bool {
+        ss := value.(*subjectStats)
+        subjects[key.(string)] = map[string]int64{
+            "request_count": atomic.LoadInt64(&ss.requestCount),
+            "error_count":   atomic.LoadInt64(&ss.errorCount),
+        }
+        return true
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusOK)
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "request_count":          atomic.LoadInt64(&totalRequestCount),
+        "error_count":            atomic.LoadInt64(&totalErrorCount),
+        "auth_failures":          atomic.LoadInt64(&authFailureCount),
+        "max_payload_size_bytes": atomic.LoadInt64(&maxPayloadSize),
+        "types":                  types,
+        "subjects":               subjects,
+    })
+}
+
+func writeMetricsPrometheus(w http.ResponseWriter) {
+    var b strings.Builder
+
+    fmt.Fprintf(&b, "# HELP format_api_requests_total Total /format requests.\n")
+    fmt.Fprintf(&b, "# TYPE format_api_requests_total counter\n")
+    fmt.Fprintf(&b, "format_api_requests_total %d\n", atomic.LoadInt64(&totalRequestCount))
+
+    fmt.Fprintf(&b, "# HELP format_api_errors_total Total /format requests that did not succeed.\n")
+    fmt.Fprintf(&b, "# TYPE format_api_errors_total counter\n")
+    fmt.Fprintf(&b, "format_api_errors_total %d\n", atomic.LoadInt64(&totalErrorCount))
+
+    fmt.Fprintf(&b, "# HELP format_api_auth_failures_total Total requests rejected by JWT auth.\n")
+    fmt.Fprintf(&b, "# TYPE format_api_auth_failures_total counter\n")
+    fmt.Fprintf(&b, "format_api_auth_failures_total %d\n", atomic.LoadInt64(&authFailureCount))
+
+    fmt.Fprintf(&b, "# HELP format_api_max_payload_bytes Largest request payload seen.\n")
+    fmt.Fprintf(&b, "# TYPE format_api_max_payload_bytes gauge\n")
+    fmt.Fprintf(&b, "format_api_max_payload_bytes %d\n", atomic.LoadInt64(&maxPayloadSize))
+
+    fmt.Fprintf(&b, "# HELP format_api_requests_by_type_total Requests per content type and status.\n")
+    fmt.Fprintf(&b, "# TYPE format_api_requests_by_type_total counter\n")
+    typeStatsMap.Range(func(key, value interface{}) bool {
+        typ := key.(string)
+        ts := value.(*typeStats)
+        for s := formatStatus(0); s < numFormatStatuses; s++ {
+            fmt.Fprintf(&b, "format_api_requests_by_type_total{type=%q,status=%q} %d\n",
+                typ, s.String(), atomic.LoadInt64(&ts.counts[s]))
+        }
+        return true
+    })
+
+    fmt.Fprintf(&b, "# HELP format_api_request_duration_microseconds Request duration by content type.\n")
+    fmt.Fprintf(&b, "# TYPE format_api_request_duration_microseconds histogram\n")
+    typeStatsMap.Range(func(key, value interface{}) bool {
+        writeHistogramPrometheus(&b, "format_api_request_duration_microseconds", key.(string), value.(*typeStats).duration)
+        return true
+    })
+
+    fmt.Fprintf(&b, "# HELP format_api_request_payload_bytes Request payload size by content type.\n")
+    fmt.Fprintf(&b, "# TYPE format_api_request_payload_bytes histogram\n")
+    typeStatsMap.Range(func(key, value interface{}) bool {
+        writeHistogramPrometheus(&b, "format_api_request_payload_bytes", key.(string), value.(*typeStats).payload)
+        return true
+    })
+
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+    w.WriteHeader(http.StatusOK)
+    w.Write([]byte(b.String()))
+}
+
+func writeHistogramPrometheus(b *strings.Builder, name, typ string, h *histogram) {
+    snap := h.snapshot()
+    for i, bound := range snap.Bounds {
+        fmt.Fprintf(b, "%s_bucket{type=%q,le=%q} %d\n", name, typ, strconv.FormatInt(bound, 10), snap.Counts[i])
+    }
+    fmt.Fprintf(b, "%s_bucket{type=%q,le=\"+Inf\"} %d\n", name, typ, snap.Total)
+    fmt.Fprintf(b, "%s_sum{type=%q} %d\n", name, typ, snap.Sum)
+    fmt.Fprintf(b, "%s_count{type=%q} %d\n", name, typ, snap.Total)
+}
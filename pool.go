@@ -0,0 +1,33 @@
+package main
+
+import (
+    "bytes"
+    "sync"
+)
+
+// maxPooledBufferBytes caps the capacity of a buffer we'll return to the
+// pool. Without this, one request for a huge payload would permanently
+// grow the pool's steady-state memory usage.
+const maxPooledBufferBytes = 4 << 20 // 4 MiB
+
+// bufferPool is shared by formatJSON, formatXML, and formatHTML to avoid
+// allocating a fresh output buffer on every request.
+var bufferPool = sync.Pool{
+    New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// getBuffer returns a zeroed buffer from the pool.
+func getBuffer() *bytes.Buffer {
+    buf := bufferPool.Get().(*bytes.Buffer)
+    buf.Reset()
+    return buf
+}
+
+// putBuffer returns buf to the pool, unless it grew too large to be
+// worth retaining.
+func putBuffer(buf *bytes.Buffer) {
+    if buf.Cap() > maxPooledBufferBytes {
+        return
+    }
+    bufferPool.Put(buf)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// streamFlushThreshold is how much buffered output FormatStream
+// implementations should accumulate before calling bw.Flush(), so a
+// multi-hundred-MB document is written to the client in bounded chunks
+// rather than all at once.
+const streamFlushThreshold = 32 * 1024
+
+// streamDeadlineMargin is how long before the server's WriteTimeout a
+// streaming formatter should stop and wind down, leaving enough time to
+// emit either a truncation marker or a clean error response.
+const streamDeadlineMargin = 500 * time.Millisecond
+
+// errStreamDeadline is returned by a StreamFormatter when streamGuard
+// reports the write deadline is approaching.
+var errStreamDeadline = errors.New("stream: write deadline approaching")
+
+// StreamFormatter is implemented by formatters that can reformat input
+// incrementally instead of buffering the whole result. Formatters that
+// don't implement it simply aren't eligible for ?stream=true.
+type StreamFormatter interface {
+    FormatStream(r io.Reader, bw *bufio.Writer, opts FormatOptions, guard *streamGuard) error
+}
+
+// streamGuard tracks the server write deadline for a single streaming
+// request and whether any bytes have actually reached the network yet.
+type streamGuard struct {
+    deadline time.Time // zero means no deadline is enforced
+    wrote    bool
+}
+
+func newStreamGuard(writeTimeout time.Duration) *streamGuard {
+    g := &streamGuard{}
+    if writeTimeout > streamDeadlineMargin {
+        g.deadline = time.Now().Add(writeTimeout - streamDeadlineMargin)
+    }
+    return g
+}
+
+// expired reports whether the write deadline is imminent. Call this
+// between tokens/elements in a FormatStream loop.
+func (g *streamGuard) expired() bool {
+    return !g.deadline.IsZero() && time.Now().After(g.deadline)
+}
+
+// trackingWriter wraps the ResponseWriter so streamGuard can tell
+// whether any bytes have actually been written to the client yet, which
+// determines whether we can still fall back to a clean error response.
+type trackingWriter struct {
+    w     http.ResponseWriter
+    guard *streamGuard
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+    t.guard.wrote = true
+    return t.w.Write(p)
+}
+
+// streamFormatHandler serves formatHandler's ?stream=true path: it
+// reformats the request body incrementally via a StreamFormatter,
+// respecting the server's write deadline instead of leaking a
+// half-written chunked body if formatting runs long.
+func streamFormatHandler(w http.ResponseWriter, r *http.Request, formatter Formatter, contentType string, opts FormatOptions, writeTimeout time.Duration) {
+    sf, ok := formatter.(StreamFormatter)
+    if !ok {
+        http.Error(w, fmt.Sprintf("streaming is not supported for type %q", contentType), http.StatusBadRequest)
+        return
+    }
+
+    w.Header().Set("Content-Type", contentTypeFor(contentType))
+
+    guard := newStreamGuard(writeTimeout)
+    tw := &trackingWriter{w: w, guard: guard}
+    bw := bufio.NewWriterSize(tw, streamFlushThreshold)
+
+    err := sf.FormatStream(r.Body, bw, opts, guard)
+    if err == nil {
+        if flushErr := bw.Flush(); flushErr != nil {
+            log.Printf("Error flushing streamed response: %v", flushErr)
+        }
+        return
+    }
+
+    if !errors.Is(err, errStreamDeadline) {
+        log.Printf("Streaming format failed: %v", err)
+        if !guard.wrote {
+            writeFixedLengthError(w, fmt.Sprintf("Formatting failed: %s", err.Error()), http.StatusInternalServerError)
+        }
+        // Headers/body already partially sent; nothing more we can do
+        // without corrupting the chunked stream.
+        return
+    }
+
+    if !guard.wrote {
+        // Nothing has reached the client yet, so we can still send a
+        // well-formed, non-chunked error instead of streamed output.
+        writeFixedLengthError(w, "Formatting timed out before any output was produced", http.StatusServiceUnavailable)
+        return
+    }
+
+    // Output is already in flight as a chunked response; flush whatever
+    // the formatter already wound down with and stop cleanly rather
+    // than leaving the body truncated mid-token.
+    if flushErr := bw.Flush(); flushErr != nil {
+        log.Printf("Error flushing truncated streamed response: %v", flushErr)
+    }
+}
+
+// writeFixedLengthError writes msg with an explicit Content-Length so
+// the response is not chunked, used on the streaming error path where
+// nothing has been written to the client yet.
+func writeFixedLengthError(w http.ResponseWriter, msg string, status int) {
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    w.Header().Set("Content-Length", strconv.Itoa(len(msg)+1))
+    w.WriteHeader(status)
+    fmt.Fprintln(w, msg)
+}
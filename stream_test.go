@@ -0,0 +1,105 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestStreamGuardExpired(t *testing.T) {
+    cases := []struct {
+        name     string
+        guard    *streamGuard
+        expired  bool
+    }{
+        {"zero deadline never expires", &streamGuard{}, false},
+        {"future deadline not yet expired", &streamGuard{deadline: time.Now().Add(time.Hour)}, false},
+        {"past deadline is expired", &streamGuard{deadline: time.Now().Add(-time.Hour)}, true},
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            if got := c.guard.expired(); got != c.expired {
+                t.Errorf("expired() = %v, want %v", got, c.expired)
+            }
+        })
+    }
+}
+
+func TestNewStreamGuard(t *testing.T) {
+    if g := newStreamGuard(streamDeadlineMargin); !g.deadline.IsZero() {
+        t.Errorf("writeTimeout <= margin should leave no deadline, got %v", g.deadline)
+    }
+    if g := newStreamGuard(streamDeadlineMargin + time.Second); g.deadline.IsZero() {
+        t.Error("writeTimeout above margin should set a deadline")
+    }
+}
+
+func TestJSONFormatStream_Basic(t *testing.T) {
+    var buf bytes.Buffer
+    bw := bufio.NewWriter(&buf)
+    guard := &streamGuard{}
+
+    err := (jsonFormatter{}).FormatStream(strings.NewReader(`[1,2,3]`), bw, FormatOptions{IndentWidth: 2}, guard)
+    if err != nil {
+        t.Fatalf("FormatStream returned error: %v", err)
+    }
+    bw.Flush()
+
+    out := buf.String()
+    if !strings.Contains(out, "1") || !strings.Contains(out, "2") || !strings.Contains(out, "3") {
+        t.Errorf("output missing expected elements: %q", out)
+    }
+    if !strings.HasPrefix(strings.TrimSpace(out), "[") || !strings.HasSuffix(strings.TrimSpace(out), "]") {
+        t.Errorf("output is not a well-formed array: %q", out)
+    }
+}
+
+func TestJSONFormatStream_Truncation(t *testing.T) {
+    var buf bytes.Buffer
+    bw := bufio.NewWriter(&buf)
+    guard := &streamGuard{deadline: time.Now().Add(-time.Minute)}
+
+    err := (jsonFormatter{}).FormatStream(strings.NewReader(`[1,2,3]`), bw, FormatOptions{IndentWidth: 2}, guard)
+    if !errors.Is(err, errStreamDeadline) {
+        t.Fatalf("FormatStream error = %v, want errStreamDeadline", err)
+    }
+    bw.Flush()
+
+    out := buf.String()
+    if !strings.Contains(out, "truncated") {
+        t.Errorf("truncated output missing truncation marker: %q", out)
+    }
+    if !strings.HasSuffix(strings.TrimSpace(out), "]") {
+        t.Errorf("truncated output does not close the array: %q", out)
+    }
+}
+
+func TestStreamFormatHandler_Success(t *testing.T) {
+    req := httptest.NewRequest("POST", "/format?type=json&stream=true", strings.NewReader(`[1,2]`))
+    rec := httptest.NewRecorder()
+
+    streamFormatHandler(rec, req, jsonFormatter{}, "json", FormatOptions{IndentWidth: 2}, time.Minute)
+
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+    }
+    if ct := rec.Header().Get("Content-Type"); ct != contentTypeFor("json") {
+        t.Errorf("Content-Type = %q, want %q", ct, contentTypeFor("json"))
+    }
+}
+
+func TestStreamFormatHandler_UnsupportedFormatter(t *testing.T) {
+    req := httptest.NewRequest("POST", "/format?type=sql&stream=true", strings.NewReader(`select 1`))
+    rec := httptest.NewRecorder()
+
+    streamFormatHandler(rec, req, FormatterFunc(formatSQL), "sql", FormatOptions{}, time.Minute)
+
+    if rec.Code != http.StatusBadRequest {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+    }
+}